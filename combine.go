@@ -1,10 +1,17 @@
 package combine
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"reflect"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
 )
 
 // Option configures Combine instance.
@@ -24,6 +31,118 @@ func WithCtx(ctx map[string]any) Option {
 	}
 }
 
+// WithContinueOnError makes Process keep running the remaining aggregate
+// tasks after one of them fails, instead of aborting on the first error.
+// All per-task errors are joined (via errors.Join) into the error Process
+// returns, so a failing tag never prevents the others from writing back.
+//
+// Without this option Process is fail-fast: in concurrent mode the first
+// task error cancels its siblings via errgroup and only that first error is
+// returned, matching the sequential path's stop-on-first-error behavior.
+// The full errors.Join accumulation described above only happens once this
+// option is set.
+func WithContinueOnError() Option {
+	return func(c *Combine) {
+		c.continueOnError = true
+	}
+}
+
+// WithHandlerTimeout wraps every handler invocation in a context.WithTimeout
+// of duration d, on top of whatever deadline the caller's context already
+// carries. Use WithHandlerTimeoutFor to override it for a specific handler.
+func WithHandlerTimeout(d time.Duration) Option {
+	return func(c *Combine) {
+		c.handlerTimeout = d
+	}
+}
+
+// WithHandlerTimeoutFor sets a per-handler timeout that overrides the
+// default set by WithHandlerTimeout for the handler registered as name.
+func WithHandlerTimeoutFor(name string, d time.Duration) Option {
+	return func(c *Combine) {
+		if c.handlerTimeouts == nil {
+			c.handlerTimeouts = make(map[string]time.Duration)
+		}
+		c.handlerTimeouts[name] = d
+	}
+}
+
+// KeyFunc computes a dedup/cache key for an aggregate input value. Two
+// values that should be treated as the same handler input (and therefore
+// collapsed into a single call) must hash to equal keys.
+type KeyFunc func(v any) any
+
+// defaultKeyFunc keys by the value's %#v representation, which agrees with
+// reflect.DeepEqual for the struct/slice/map field values Process typically
+// sees, while staying a comparable map key regardless of the field's type.
+func defaultKeyFunc(v any) any {
+	return fmt.Sprintf("%#v", v)
+}
+
+// WithKeyFunc overrides how Process collapses duplicate handler inputs
+// before dispatch. The default treats values as equal when they are
+// reflect.DeepEqual.
+func WithKeyFunc(fn KeyFunc) Option {
+	return func(c *Combine) {
+		c.keyFunc = fn
+	}
+}
+
+// Cache lets a registered handler's results survive across Process calls,
+// turning Combine into a batching loader that also short-circuits repeat
+// lookups (the GraphQL dataloader pattern). Get/Set keys combine the
+// handler name with the KeyFunc-derived key for the input value.
+type Cache interface {
+	Get(key any) (any, bool)
+	Set(key any, val any)
+}
+
+// WithCache attaches cache as the result cache for the handler registered as
+// name. Before invoking that handler, Process checks cache for each
+// deduplicated input and only calls the handler for misses; each fresh
+// result for a miss is then stored in cache (cache hits are not re-stored).
+func WithCache(name string, cache Cache) Option {
+	return func(c *Combine) {
+		if c.caches == nil {
+			c.caches = make(map[string]Cache)
+		}
+		c.caches[name] = cache
+	}
+}
+
+// cacheKey scopes a KeyFunc-derived key to the handler it belongs to, so one
+// Cache instance can safely be shared across handlers registered under
+// different names.
+type cacheKey struct {
+	handler string
+	key     any
+}
+
+// WithMaxDepth overrides the default recursion bound (defaultMaxDepth) Process
+// uses when descending into nested struct/pointer/slice/array/map fields
+// looking for `combine` tags. A field's own combine:"...,depth=N" modifier
+// overrides this for the subtree rooted at that field.
+func WithMaxDepth(n int) Option {
+	return func(c *Combine) {
+		c.maxDepth = n
+	}
+}
+
+// DependsOn declares that the handler registered as name must not run until
+// dep has finished and written its results back. This is the programmatic
+// equivalent of a combine:"name,Output,after=dep" tag modifier; Process
+// merges both sources into one dependency graph and runs handlers in
+// topological layers, executing dep's layer (and writing its results back)
+// before name's layer is even built.
+func (c *Combine) DependsOn(name, dep string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.dependencies == nil {
+		c.dependencies = make(map[string][]string)
+	}
+	c.dependencies[name] = append(c.dependencies[name], dep)
+}
+
 // AggregateHandler consumes a slice of values and component ctx, and returns a map
 // keyed by original item key to aggregated value.
 // The key can be original struct pointer, or a chosen identity extracted via field value.
@@ -40,13 +159,64 @@ func (f HandleFunc) Handle(values []any, combineCtx map[string]any) map[any]any
 	return f(values, combineCtx)
 }
 
+// AggregateHandlerE is like AggregateHandler but can report a batch-level
+// failure (e.g. a DB error while resolving combineItem) instead of being
+// forced to return a nil/partial map. Register it with RegisterE or
+// RegisterAggregateE.
+type AggregateHandlerE interface {
+	Handle(values []any, combineCtx map[string]any) (map[any]any, error)
+}
+
+// HandleFuncE is an adapter to allow the use of ordinary functions as AggregateHandlerE.
+type HandleFuncE func(values []any, combineCtx map[string]any) (map[any]any, error)
+
+// Handle calls f(values, combineCtx).
+func (f HandleFuncE) Handle(values []any, combineCtx map[string]any) (map[any]any, error) {
+	return f(values, combineCtx)
+}
+
+// AggregateHandlerContext is like AggregateHandlerE but also receives the
+// context.Context passed to ProcessContext, so a handler can respect
+// cancellation and deadlines (e.g. abort a slow DB lookup in combineItem).
+// Register it with RegisterContext or RegisterAggregateContext.
+type AggregateHandlerContext interface {
+	HandleContext(ctx context.Context, values []any, combineCtx map[string]any) (map[any]any, error)
+}
+
+// HandleContextFunc is an adapter to allow the use of ordinary functions as
+// AggregateHandlerContext.
+type HandleContextFunc func(ctx context.Context, values []any, combineCtx map[string]any) (map[any]any, error)
+
+// HandleContext calls f(ctx, values, combineCtx).
+func (f HandleContextFunc) HandleContext(ctx context.Context, values []any, combineCtx map[string]any) (map[any]any, error) {
+	return f(ctx, values, combineCtx)
+}
+
+// aggregateFunc is the normalized form every registered handler is reduced
+// to, so the rest of the package only has one calling convention to deal
+// with regardless of which public interface was used to register it.
+type aggregateFunc func(ctx context.Context, values []any, combineCtx map[string]any) (map[any]any, error)
+
 // Combine is the component root.
 type Combine struct {
-	concurrent bool
-	combineCtx map[string]any
+	concurrent      bool
+	continueOnError bool
+	combineCtx      map[string]any
+
+	handlerTimeout  time.Duration
+	handlerTimeouts map[string]time.Duration
+
+	keyFunc  KeyFunc
+	caches   map[string]Cache
+	maxDepth int
+
+	// dependencies maps a handler name to the handlers that must finish (and
+	// write back) before it runs, set via DependsOn. Process merges these
+	// with any combine:"...,after=..." tag modifiers found during traversal.
+	dependencies map[string][]string
 
 	// registries
-	aggregateHandlers map[string]AggregateHandler
+	aggregateHandlers map[string]aggregateFunc
 
 	mu sync.RWMutex
 }
@@ -56,7 +226,8 @@ func New(opts ...Option) *Combine {
 	c := &Combine{
 		concurrent:        false,
 		combineCtx:        make(map[string]any),
-		aggregateHandlers: make(map[string]AggregateHandler),
+		keyFunc:           defaultKeyFunc,
+		aggregateHandlers: make(map[string]aggregateFunc),
 	}
 	for _, opt := range opts {
 		opt(c)
@@ -75,31 +246,122 @@ func (c *Combine) Register(name string, fn AggregateHandler) {
 	if fn == nil {
 		panic(fmt.Errorf("nil handler for %s", name))
 	}
-	c.aggregateHandlers[name] = fn
+	c.aggregateHandlers[name] = func(ctx context.Context, values []any, combineCtx map[string]any) (map[any]any, error) {
+		return fn.Handle(values, combineCtx), nil
+	}
 }
 
 // RegisterAggregate explicitly registers an aggregate handler.
 func (c *Combine) RegisterAggregate(name string, fn AggregateHandler) { c.Register(name, fn) }
 
-// tagSpec parsed from struct tag: "funcName,outputTarget"
+// RegisterE registers an aggregate handler that can report a batch-level
+// error through AggregateHandlerE, alongside handlers registered via Register.
+func (c *Combine) RegisterE(name string, fn AggregateHandlerE) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if fn == nil {
+		panic(fmt.Errorf("nil handler for %s", name))
+	}
+	c.aggregateHandlers[name] = func(ctx context.Context, values []any, combineCtx map[string]any) (map[any]any, error) {
+		return fn.Handle(values, combineCtx)
+	}
+}
+
+// RegisterAggregateE explicitly registers an error-returning aggregate handler.
+func (c *Combine) RegisterAggregateE(name string, fn AggregateHandlerE) { c.RegisterE(name, fn) }
+
+// RegisterContext registers an aggregate handler that receives the
+// context.Context passed to ProcessContext, alongside handlers registered
+// via Register/RegisterE. Process (and a context-less ProcessContext caller)
+// invoke it with context.Background().
+func (c *Combine) RegisterContext(name string, fn AggregateHandlerContext) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if fn == nil {
+		panic(fmt.Errorf("nil handler for %s", name))
+	}
+	c.aggregateHandlers[name] = fn.HandleContext
+}
+
+// RegisterAggregateContext explicitly registers a context-aware aggregate handler.
+func (c *Combine) RegisterAggregateContext(name string, fn AggregateHandlerContext) {
+	c.RegisterContext(name, fn)
+}
+
+// effectiveMaxDepth returns the configured WithMaxDepth value, or
+// defaultMaxDepth if it was never set.
+func (c *Combine) effectiveMaxDepth() int {
+	if c.maxDepth > 0 {
+		return c.maxDepth
+	}
+	return defaultMaxDepth
+}
+
+// timeoutFor returns the timeout to apply to the handler registered as name,
+// preferring a WithHandlerTimeoutFor override over the WithHandlerTimeout
+// default. The caller must hold c.mu.
+func (c *Combine) timeoutFor(name string) (time.Duration, bool) {
+	if d, ok := c.handlerTimeouts[name]; ok {
+		return d, true
+	}
+	if c.handlerTimeout > 0 {
+		return c.handlerTimeout, true
+	}
+	return 0, false
+}
+
+// tagSpec parsed from struct tag: "funcName,outputTarget[,modifier=value...]"
 type tagSpec struct {
 	funcName     string
 	outputTarget string // field name or fn:Method
+
+	skip        bool // combine:"-": skip this field and its subtree entirely
+	maxDepth    int  // combine:"...,depth=N": recursion bound for this field's subtree
+	hasMaxDepth bool
+	after       []string // combine:"...,after=dep1;dep2": handlers this tag's funcName must run after
 }
 
+// defaultMaxDepth bounds how many nested struct/slice/map levels Process
+// will descend into when WithMaxDepth hasn't been set, so a field with
+// unexpected structural recursion can't run away.
+const defaultMaxDepth = 8
+
 func parseTag(tag string) (tagSpec, bool) {
 	if tag == "" {
 		return tagSpec{}, false
 	}
-	parts := strings.Split(tag, ",")
-	if len(parts) == 0 {
-		return tagSpec{}, false
+	if tag == "-" {
+		return tagSpec{skip: true}, true
 	}
+
+	parts := strings.Split(tag, ",")
 	spec := tagSpec{funcName: strings.TrimSpace(parts[0])}
 	if len(parts) > 1 {
 		spec.outputTarget = strings.TrimSpace(parts[1])
 	}
-	if spec.funcName == "" {
+	var mods []string
+	if len(parts) > 2 {
+		mods = parts[2:]
+	}
+	for _, mod := range mods {
+		mod = strings.TrimSpace(mod)
+		if rest, ok := strings.CutPrefix(mod, "depth="); ok {
+			if n, err := strconv.Atoi(rest); err == nil {
+				spec.maxDepth = n
+				spec.hasMaxDepth = true
+			}
+			continue
+		}
+		if rest, ok := strings.CutPrefix(mod, "after="); ok {
+			for _, dep := range strings.Split(rest, ";") {
+				if dep = strings.TrimSpace(dep); dep != "" {
+					spec.after = append(spec.after, dep)
+				}
+			}
+		}
+	}
+
+	if spec.funcName == "" && !spec.hasMaxDepth {
 		return tagSpec{}, false
 	}
 	return spec, true
@@ -107,7 +369,22 @@ func parseTag(tag string) (tagSpec, bool) {
 
 // Process scans elements in items and applies handlers based on `combine` tag.
 // items must be a slice of struct instances or pointers to structs, typed as []any.
+// It is equivalent to ProcessContext(context.Background(), items).
 func (c *Combine) Process(items []any) error {
+	return c.ProcessContext(context.Background(), items)
+}
+
+// ProcessContext is like Process but threads ctx through to every handler
+// invocation, so handlers registered via RegisterContext can observe
+// cancellation and deadlines, and WithHandlerTimeout(For) can bound how long
+// any single handler is allowed to run. ctx is also checked before the first
+// pass and before/after each task dispatch, so an already-canceled ctx short
+// circuits without invoking any handler.
+func (c *Combine) ProcessContext(ctx context.Context, items []any) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	n := len(items)
 	if n == 0 {
 		return nil
@@ -115,15 +392,81 @@ func (c *Combine) Process(items []any) error {
 
 	// We will collect per-tag data for aggregate handlers.
 	type fieldRef struct {
-		itemValue reflect.Value // struct or pointer to struct
-		fieldIdx  int
-		fieldVal  any
-		output    string // may be field name or fn:Method
+		parent   reflect.Value // addressable struct containing the field, however deeply nested
+		fieldIdx int
+		output   string // may be field name or fn:Method
 	}
 
 	// map funcName -> list of fieldRefs
 	aggregates := map[string][]fieldRef{}
 
+	// tagDeps collects combine:"...,after=dep" modifiers found during
+	// traversal; merged with DependsOn-registered dependencies below.
+	tagDeps := map[string]map[string]bool{}
+	addTagDep := func(name string, deps []string) {
+		if len(deps) == 0 {
+			return
+		}
+		if tagDeps[name] == nil {
+			tagDeps[name] = map[string]bool{}
+		}
+		for _, d := range deps {
+			tagDeps[name][d] = true
+		}
+	}
+
+	// First pass: recursively traverse each item's fields (and any nested
+	// struct/pointer/slice/array/map-of-struct fields) collecting aggregate
+	// inputs. Recursion is bounded by maxDepth, which a field's own
+	// combine:"...,depth=N" modifier can override for its own subtree, and
+	// combine:"-" skips a field (and everything under it) entirely.
+	var traverse func(structVal reflect.Value, depth, maxDepth int) error
+	traverse = func(structVal reflect.Value, depth, maxDepth int) error {
+		if structVal.Kind() != reflect.Struct {
+			return fmt.Errorf("combine: expected struct, got %s", structVal.Kind())
+		}
+
+		t := structVal.Type()
+		for fi := 0; fi < t.NumField(); fi++ {
+			field := t.Field(fi)
+			fv := structVal.Field(fi)
+			tag := field.Tag.Get("combine")
+			spec, ok := parseTag(tag)
+			if ok && spec.skip {
+				continue
+			}
+
+			if ok && spec.funcName != "" {
+				c.mu.RLock()
+				_, isAgg := c.aggregateHandlers[spec.funcName]
+				c.mu.RUnlock()
+				if !isAgg {
+					return fmt.Errorf("handler %s not registered", spec.funcName)
+				}
+
+				addTagDep(spec.funcName, spec.after)
+				aggregates[spec.funcName] = append(aggregates[spec.funcName], fieldRef{
+					parent:   structVal,
+					fieldIdx: fi,
+					output:   spec.outputTarget,
+				})
+				continue
+			}
+
+			childMaxDepth := maxDepth
+			if ok && spec.hasMaxDepth {
+				childMaxDepth = spec.maxDepth
+			}
+			if depth >= childMaxDepth {
+				continue
+			}
+			if err := traverseField(fv, depth, childMaxDepth, traverse); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
 	// First pass: traverse all items and fields, collect aggregate inputs.
 	for i := 0; i < n; i++ {
 		elem := reflect.ValueOf(items[i])
@@ -138,38 +481,79 @@ func (c *Combine) Process(items []any) error {
 			return fmt.Errorf("slice element must be struct or *struct")
 		}
 
-		t := structVal.Type()
-		for fi := 0; fi < t.NumField(); fi++ {
-			field := t.Field(fi)
-			tag := field.Tag.Get("combine")
-			spec, ok := parseTag(tag)
-			if !ok {
-				continue
-			}
+		if err := traverse(structVal, 1, c.effectiveMaxDepth()); err != nil {
+			return err
+		}
+	}
 
-			// read current value
-			fv := structVal.Field(fi)
-			var value any
-			if fv.IsValid() {
-				value = fv.Interface()
+	// Build the dependency graph for just the handlers present this call,
+	// merging tag-declared (combine:"...,after=...") and DependsOn-declared
+	// edges, then schedule them into topological layers: within a layer the
+	// existing concurrent/sequential behavior applies; a layer only starts
+	// once every layer before it has run and written back.
+	c.mu.RLock()
+	declared := c.dependencies
+	c.mu.RUnlock()
+
+	deps := map[string]map[string]bool{}
+	for name, ds := range declared {
+		for _, d := range ds {
+			if deps[name] == nil {
+				deps[name] = map[string]bool{}
 			}
+			deps[name][d] = true
+		}
+	}
+	for name, ds := range tagDeps {
+		if deps[name] == nil {
+			deps[name] = map[string]bool{}
+		}
+		for d := range ds {
+			deps[name][d] = true
+		}
+	}
 
-			c.mu.RLock()
-			_, isAgg := c.aggregateHandlers[spec.funcName]
-			c.mu.RUnlock()
+	names := make([]string, 0, len(aggregates))
+	for name := range aggregates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
 
-			if isAgg {
-				aggregates[spec.funcName] = append(aggregates[spec.funcName], fieldRef{
-					itemValue: elem,
-					fieldIdx:  fi,
-					fieldVal:  value,
-					output:    spec.outputTarget,
-				})
+	remaining := make(map[string]bool, len(names))
+	for _, name := range names {
+		remaining[name] = true
+	}
+
+	var layers [][]string
+	for len(remaining) > 0 {
+		var layer []string
+		for _, name := range names {
+			if !remaining[name] {
 				continue
 			}
-
-			return fmt.Errorf("handler %s not registered", spec.funcName)
+			ready := true
+			for dep := range deps[name] {
+				if remaining[dep] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				layer = append(layer, name)
+			}
 		}
+		if len(layer) == 0 {
+			stuck := make([]string, 0, len(remaining))
+			for name := range remaining {
+				stuck = append(stuck, name)
+			}
+			sort.Strings(stuck)
+			return fmt.Errorf("combine: dependency cycle detected among handlers: %s", strings.Join(stuck, ", "))
+		}
+		for _, name := range layer {
+			delete(remaining, name)
+		}
+		layers = append(layers, layer)
 	}
 
 	// Aggregates: per funcName, gather values and invoke handler.
@@ -179,84 +563,252 @@ func (c *Combine) Process(items []any) error {
 		values []any
 	}
 
-	tasks := make([]aggTask, 0, len(aggregates))
-	for name, refs := range aggregates {
+	buildTask := func(name string) aggTask {
+		refs := aggregates[name]
 		vals := make([]any, 0, len(refs))
 		for _, r := range refs {
-			vals = append(vals, r.fieldVal)
+			var v any
+			if fv := r.parent.Field(r.fieldIdx); fv.IsValid() {
+				v = fv.Interface()
+			}
+			vals = append(vals, v)
 		}
-		tasks = append(tasks, aggTask{name: name, refs: refs, values: vals})
+		return aggTask{name: name, refs: refs, values: vals}
 	}
 
-	runTask := func(task aggTask) error {
+	runTask := func(ctx context.Context, task aggTask) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
 		c.mu.RLock()
 		handler := c.aggregateHandlers[task.name]
-		ctx := c.combineCtx
+		cctx := c.combineCtx
+		timeout, hasTimeout := c.timeoutFor(task.name)
 		c.mu.RUnlock()
 		if handler == nil {
 			return fmt.Errorf("aggregate handler %s not found", task.name)
 		}
-		result := handler.Handle(task.values, ctx)
-
-		// Write back results by matching order to refs. We assume handler keyed by original value or position.
-		// Design doc leaves keying flexible; we will match by position index if numeric keys 0..n-1 are present,
-		// otherwise try direct value match; else fall back to sequential mapping.
-		for idx, ref := range task.refs {
-			var out any
-			// try index key
-			if v, ok := result[idx]; ok {
-				out = v
-			} else if v, ok := result[ref.fieldVal]; ok {
-				out = v
-			} else {
-				// sequential fallback
-				// collect any remaining value from map (non-deterministic). To keep deterministic, use index fallback to nil.
-				out = nil
+
+		hctx := ctx
+		if hasTimeout {
+			var cancel context.CancelFunc
+			hctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+
+		c.mu.RLock()
+		keyFunc := c.keyFunc
+		cache := c.caches[task.name]
+		c.mu.RUnlock()
+		if keyFunc == nil {
+			keyFunc = defaultKeyFunc
+		}
+
+		// Collapse task.values to one entry per distinct key (dataloader-style
+		// dedup), remembering which ref indices share each entry so a single
+		// handler/cache result can be fanned back out to all of them.
+		type dedupEntry struct {
+			key     any
+			value   any
+			refIdxs []int
+			out     any
+		}
+		entryIdx := map[any]int{}
+		var entries []*dedupEntry
+		for idx, v := range task.values {
+			k := keyFunc(v)
+			if i, ok := entryIdx[k]; ok {
+				entries[i].refIdxs = append(entries[i].refIdxs, idx)
+				continue
 			}
+			entryIdx[k] = len(entries)
+			entries = append(entries, &dedupEntry{key: k, value: v, refIdxs: []int{idx}})
+		}
 
-			if ref.output == "" || !strings.HasPrefix(ref.output, "fn:") {
-				targetName := ""
-				if ref.output == "" {
-					// same field name
-					targetName = refNameByIndex(ref.itemValue, ref.fieldIdx)
-				} else {
-					targetName = ref.output
+		var missIdxs []int
+		miss := make([]any, 0, len(entries))
+		for i, e := range entries {
+			if cache != nil {
+				if v, ok := cache.Get(cacheKey{handler: task.name, key: e.key}); ok {
+					e.out = v
+					continue
 				}
-				if err := setField(derefIfPtr(ref.itemValue), targetName, out); err != nil {
-					return err
+			}
+			missIdxs = append(missIdxs, i)
+			miss = append(miss, e.value)
+		}
+
+		if len(miss) > 0 {
+			result, err := handler(hctx, miss, cctx)
+			if err != nil {
+				return fmt.Errorf("aggregate handler %s: %w", task.name, err)
+			}
+			for pos, i := range missIdxs {
+				e := entries[i]
+				// Match by position in the deduplicated input first (the
+				// common case), falling back to a direct value lookup, then
+				// to a deterministic nil.
+				if v, ok := result[pos]; ok {
+					e.out = v
+				} else if v, ok := result[e.value]; ok {
+					e.out = v
 				}
-			} else {
-				method := strings.TrimPrefix(ref.output, "fn:")
-				if err := callOutputFunc(ref.itemValue, method, out); err != nil {
-					return err
+				if cache != nil {
+					cache.Set(cacheKey{handler: task.name, key: e.key}, e.out)
 				}
 			}
 		}
-		return nil
+
+		var refErrs []error
+		for _, e := range entries {
+			out := e.out
+			for _, idx := range e.refIdxs {
+				ref := task.refs[idx]
+
+				if ref.output == "" || !strings.HasPrefix(ref.output, "fn:") {
+					targetName := ""
+					if ref.output == "" {
+						// same field name
+						targetName = refNameByIndex(ref.parent, ref.fieldIdx)
+					} else {
+						targetName = ref.output
+					}
+					if err := setField(ref.parent, targetName, out); err != nil {
+						refErrs = append(refErrs, fmt.Errorf("%s[%d]: %w", task.name, idx, err))
+					}
+				} else {
+					method := strings.TrimPrefix(ref.output, "fn:")
+					if err := callOutputFunc(ref.parent, method, out); err != nil {
+						refErrs = append(refErrs, fmt.Errorf("%s[%d]: %w", task.name, idx, err))
+					}
+				}
+			}
+		}
+		return errors.Join(refErrs...)
 	}
 
-	if c.concurrent {
-		var wg sync.WaitGroup
-		var firstErr error
-		var once sync.Once
+	// runLayer executes every task of one topological layer, applying the
+	// same concurrent/sequential and continue-on-error behavior Process has
+	// always had within a single batch.
+	runLayer := func(ctx context.Context, tasks []aggTask) error {
+		if c.concurrent {
+			if c.continueOnError {
+				// Every task must run to completion regardless of siblings
+				// failing, so there is no shared cancellation here: each task
+				// only observes ctx itself, not a group-derived child context.
+				var wg sync.WaitGroup
+				var mu sync.Mutex
+				var errs []error
+				for _, task := range tasks {
+					wg.Add(1)
+					t := task
+					go func() {
+						defer wg.Done()
+						if err := runTask(ctx, t); err != nil {
+							mu.Lock()
+							errs = append(errs, err)
+							mu.Unlock()
+						}
+					}()
+				}
+				wg.Wait()
+				return errors.Join(errs...)
+			}
+
+			g, gctx := errgroup.WithContext(ctx)
+			for _, task := range tasks {
+				t := task
+				g.Go(func() error {
+					return runTask(gctx, t)
+				})
+			}
+			return g.Wait()
+		}
+
+		var errs []error
 		for _, task := range tasks {
-			wg.Add(1)
-			t := task
-			go func() {
-				defer wg.Done()
-				if err := runTask(t); err != nil {
-					once.Do(func() { firstErr = err })
+			if err := ctx.Err(); err != nil {
+				if c.continueOnError {
+					errs = append(errs, err)
+					break
+				}
+				return err
+			}
+			if err := runTask(ctx, task); err != nil {
+				if !c.continueOnError {
+					return err
 				}
-			}()
+				errs = append(errs, err)
+			}
 		}
-		wg.Wait()
-		return firstErr
+		return errors.Join(errs...)
 	}
 
-	for _, task := range tasks {
-		if err := runTask(task); err != nil {
+	// Run layers in order: a layer's tasks are only built (reading live
+	// field values) once every earlier layer has finished and written back,
+	// so a dependent handler sees its dependency's output.
+	var errs []error
+	for _, layer := range layers {
+		if err := ctx.Err(); err != nil {
+			if c.continueOnError {
+				errs = append(errs, err)
+				break
+			}
 			return err
 		}
+
+		tasks := make([]aggTask, 0, len(layer))
+		for _, name := range layer {
+			tasks = append(tasks, buildTask(name))
+		}
+
+		if err := runLayer(ctx, tasks); err != nil {
+			if !c.continueOnError {
+				return errors.Join(append(errs, err)...)
+			}
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// traverseField descends into fv when it is a struct, a pointer to struct,
+// a slice/array of struct (or pointer to struct), or a map with struct (or
+// pointer to struct) values, calling visit on every struct it finds with
+// depth incremented by one relative to the struct fv was a field of. Any
+// other field kind is left alone. Struct values reached through a map of
+// plain (non-pointer) struct values are not addressable, so a combine tag
+// found there will fail at writeback time rather than during traversal.
+func traverseField(fv reflect.Value, depth, maxDepth int, visit func(structVal reflect.Value, depth, maxDepth int) error) error {
+	switch fv.Kind() {
+	case reflect.Struct:
+		return visit(fv, depth+1, maxDepth)
+	case reflect.Ptr:
+		if fv.IsNil() || fv.Elem().Kind() != reflect.Struct {
+			return nil
+		}
+		return visit(fv.Elem(), depth+1, maxDepth)
+	case reflect.Slice, reflect.Array:
+		elemKind := fv.Type().Elem().Kind()
+		if elemKind != reflect.Struct && elemKind != reflect.Ptr {
+			return nil
+		}
+		for i := 0; i < fv.Len(); i++ {
+			if err := traverseField(fv.Index(i), depth, maxDepth, visit); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		elemKind := fv.Type().Elem().Kind()
+		if elemKind != reflect.Struct && elemKind != reflect.Ptr {
+			return nil
+		}
+		for _, k := range fv.MapKeys() {
+			if err := traverseField(fv.MapIndex(k), depth, maxDepth, visit); err != nil {
+				return err
+			}
+		}
 	}
 	return nil
 }
@@ -338,5 +890,3 @@ func callOutputFunc(item reflect.Value, method string, arg any) error {
 	m.Call([]reflect.Value{in})
 	return nil
 }
-
-
@@ -0,0 +1,64 @@
+package combine
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type ctxItem struct {
+	ID int `combine:"slowLookup,ID"`
+}
+
+func TestProcessContextCancellationStopsBeforeDispatch(t *testing.T) {
+	c := NewCombine()
+	c.Register("slowLookup", HandleFunc(func(values []any, ctx map[string]any) map[any]any {
+		return map[any]any{0: 1}
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	items := []any{&ctxItem{ID: 1}}
+	if err := c.ProcessContext(ctx, items); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestRegisterContextReceivesCtx(t *testing.T) {
+	c := NewCombine()
+	type ctxKey struct{}
+	ctx := context.WithValue(context.Background(), ctxKey{}, "trace-id")
+
+	var seen any
+	c.RegisterContext("slowLookup", HandleContextFunc(func(ctx context.Context, values []any, cctx map[string]any) (map[any]any, error) {
+		seen = ctx.Value(ctxKey{})
+		return map[any]any{0: 1}, nil
+	}))
+
+	items := []any{&ctxItem{ID: 1}}
+	if err := c.ProcessContext(ctx, items); err != nil {
+		t.Fatalf("process error: %v", err)
+	}
+	if seen != "trace-id" {
+		t.Fatalf("expected handler to observe ctx value, got %v", seen)
+	}
+}
+
+func TestWithHandlerTimeoutCancelsSlowHandler(t *testing.T) {
+	c := NewCombine(WithHandlerTimeout(10 * time.Millisecond))
+	c.RegisterContext("slowLookup", HandleContextFunc(func(ctx context.Context, values []any, cctx map[string]any) (map[any]any, error) {
+		select {
+		case <-time.After(time.Second):
+			return map[any]any{0: 1}, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}))
+
+	items := []any{&ctxItem{ID: 1}}
+	if err := c.Process(items); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+}
@@ -0,0 +1,83 @@
+package combine
+
+import (
+	"strconv"
+	"testing"
+)
+
+type dedupItem struct {
+	ID    int `combine:"combineItem,Items"`
+	Items []string
+}
+
+func TestProcessDedupesDuplicateInputs(t *testing.T) {
+	c := NewCombine()
+	calls := 0
+
+	c.Register("combineItem", HandleFunc(func(values []any, ctx map[string]any) map[any]any {
+		calls++
+		out := make(map[any]any, len(values))
+		for idx, v := range values {
+			id, _ := v.(int)
+			out[idx] = []string{"db-row", strconv.Itoa(id)}
+		}
+		return out
+	}))
+
+	a, b, cc := &dedupItem{ID: 1}, &dedupItem{ID: 1}, &dedupItem{ID: 2}
+	items := []any{a, b, cc}
+	if err := c.Process(items); err != nil {
+		t.Fatalf("process error: %v", err)
+	}
+	if calls != 1 {
+		t.Fatalf("expected a single handler invocation, got %d", calls)
+	}
+	if a.Items[1] != "1" || b.Items[1] != "1" || cc.Items[1] != "2" {
+		t.Fatalf("unexpected fan-out: %+v %+v %+v", a, b, cc)
+	}
+}
+
+type fakeCache struct {
+	store map[any]any
+	gets  int
+	sets  int
+}
+
+func newFakeCache() *fakeCache { return &fakeCache{store: make(map[any]any)} }
+
+func (f *fakeCache) Get(key any) (any, bool) {
+	f.gets++
+	v, ok := f.store[key]
+	return v, ok
+}
+
+func (f *fakeCache) Set(key any, val any) {
+	f.sets++
+	f.store[key] = val
+}
+
+func TestWithCacheSkipsRepeatedHandlerCalls(t *testing.T) {
+	cache := newFakeCache()
+	c := NewCombine(WithCache("combineItem", cache))
+	calls := 0
+
+	c.Register("combineItem", HandleFunc(func(values []any, ctx map[string]any) map[any]any {
+		calls++
+		out := make(map[any]any, len(values))
+		for idx, v := range values {
+			id, _ := v.(int)
+			out[idx] = []string{strconv.Itoa(id)}
+		}
+		return out
+	}))
+
+	if err := c.Process([]any{&dedupItem{ID: 1}}); err != nil {
+		t.Fatalf("first process error: %v", err)
+	}
+	if err := c.Process([]any{&dedupItem{ID: 1}}); err != nil {
+		t.Fatalf("second process error: %v", err)
+	}
+	if calls != 1 {
+		t.Fatalf("expected handler to run once across both Process calls, got %d", calls)
+	}
+}
@@ -0,0 +1,102 @@
+package combine
+
+import (
+	"strings"
+	"testing"
+)
+
+type depItem struct {
+	Raw     int `combine:"double,Doubled"`
+	Avg     int `combine:"avg,AvgOut,after=double"`
+	Doubled int
+	AvgOut  int
+}
+
+func TestProcessRunsDependentHandlerAfterItsDependency(t *testing.T) {
+	c := NewCombine()
+
+	var order []string
+	c.Register("double", HandleFunc(func(values []any, ctx map[string]any) map[any]any {
+		order = append(order, "double")
+		out := make(map[any]any, len(values))
+		for idx, v := range values {
+			n, _ := v.(int)
+			out[idx] = n * 2
+		}
+		return out
+	}))
+
+	// avg's tag carries after=double, so Process must run it in a later
+	// layer than double even though both are registered up front.
+	c.Register("avg", HandleFunc(func(values []any, ctx map[string]any) map[any]any {
+		order = append(order, "avg")
+		out := make(map[any]any, len(values))
+		for idx, v := range values {
+			n, _ := v.(int)
+			out[idx] = n + 1
+		}
+		return out
+	}))
+
+	item := &depItem{Raw: 5, Avg: 100}
+	if err := c.Process([]any{item}); err != nil {
+		t.Fatalf("process error: %v", err)
+	}
+	if len(order) != 2 || order[0] != "double" || order[1] != "avg" {
+		t.Fatalf("expected double before avg, got %v", order)
+	}
+	if item.Doubled != 10 {
+		t.Fatalf("expected Doubled=10, got %d", item.Doubled)
+	}
+	if item.AvgOut != 101 {
+		t.Fatalf("expected AvgOut=101, got %d", item.AvgOut)
+	}
+}
+
+func TestDependsOnCycleIsReported(t *testing.T) {
+	type cyclic struct {
+		A int `combine:"a,A,after=b"`
+		B int `combine:"b,B,after=a"`
+	}
+
+	c := NewCombine()
+	c.Register("a", HandleFunc(func(values []any, ctx map[string]any) map[any]any { return map[any]any{} }))
+	c.Register("b", HandleFunc(func(values []any, ctx map[string]any) map[any]any { return map[any]any{} }))
+
+	items := []any{&cyclic{}}
+	err := c.Process(items)
+	if err == nil {
+		t.Fatalf("expected cycle error")
+	}
+	if !strings.Contains(err.Error(), "handlers: a, b") {
+		t.Fatalf("expected cycle error to name both handlers, got %v", err)
+	}
+}
+
+func TestDependsOnProgrammaticAPIOrdersLayers(t *testing.T) {
+	type obj struct {
+		A int `combine:"first,A"`
+		B int `combine:"second,B"`
+	}
+
+	c := NewCombine()
+	c.DependsOn("second", "first")
+
+	var order []string
+	c.Register("first", HandleFunc(func(values []any, ctx map[string]any) map[any]any {
+		order = append(order, "first")
+		return map[any]any{0: 1}
+	}))
+	c.Register("second", HandleFunc(func(values []any, ctx map[string]any) map[any]any {
+		order = append(order, "second")
+		return map[any]any{0: 2}
+	}))
+
+	items := []any{&obj{}}
+	if err := c.Process(items); err != nil {
+		t.Fatalf("process error: %v", err)
+	}
+	if len(order) != 2 || order[0] != "first" || order[1] != "second" {
+		t.Fatalf("expected first before second, got %v", order)
+	}
+}
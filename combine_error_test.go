@@ -0,0 +1,82 @@
+package combine
+
+import (
+	"errors"
+	"testing"
+)
+
+type errItem struct {
+	ID    int `combine:"combineItem,Items"`
+	Items []string
+}
+
+func TestRegisterEReportsHandlerError(t *testing.T) {
+	c := NewCombine()
+	wantErr := errors.New("db unavailable")
+
+	c.RegisterE("combineItem", HandleFuncE(func(values []any, ctx map[string]any) (map[any]any, error) {
+		return nil, wantErr
+	}))
+
+	items := []any{&errItem{ID: 1}}
+	err := c.Process(items)
+	if err == nil || !errors.Is(err, wantErr) {
+		t.Fatalf("expected wrapped %v, got %v", wantErr, err)
+	}
+}
+
+func TestContinueOnErrorRunsRemainingTasks(t *testing.T) {
+	type obj struct {
+		A int `combine:"failing,A"`
+		B int `combine:"ok,B"`
+	}
+
+	c := NewCombine(WithContinueOnError())
+	c.RegisterE("failing", HandleFuncE(func(values []any, ctx map[string]any) (map[any]any, error) {
+		return nil, errors.New("boom")
+	}))
+	c.Register("ok", HandleFunc(func(values []any, ctx map[string]any) map[any]any {
+		out := make(map[any]any, len(values))
+		for idx := range values {
+			out[idx] = 42
+		}
+		return out
+	}))
+
+	o := &obj{}
+	items := []any{o}
+	err := c.Process(items)
+	if err == nil {
+		t.Fatalf("expected a joined error from the failing task")
+	}
+	if o.B != 42 {
+		t.Fatalf("expected ok task to still write back, got %+v", o)
+	}
+}
+
+func TestWithoutContinueOnErrorStopsOnFirstSequentialFailure(t *testing.T) {
+	type obj struct {
+		A int `combine:"failing,A"`
+		B int `combine:"later,B"`
+	}
+
+	c := NewCombine()
+	c.RegisterE("failing", HandleFuncE(func(values []any, ctx map[string]any) (map[any]any, error) {
+		return nil, errors.New("boom")
+	}))
+	// "later" sorts after "failing", so the sequential path reaches it only
+	// if it keeps going past the first failure.
+	laterRan := false
+	c.Register("later", HandleFunc(func(values []any, ctx map[string]any) map[any]any {
+		laterRan = true
+		return map[any]any{}
+	}))
+
+	items := []any{&obj{}}
+	if err := c.Process(items); err == nil {
+		t.Fatalf("expected error")
+	}
+	if laterRan {
+		t.Fatalf("expected Process to stop after the first failing task")
+	}
+}
@@ -0,0 +1,72 @@
+package combine
+
+import "testing"
+
+type recursiveChild struct {
+	SKU   string `combine:"priceLookup,Price"`
+	Price float64
+}
+
+type recursiveParent struct {
+	Name     string
+	Child    recursiveChild
+	Children []*recursiveChild
+	Deep     struct {
+		Skipped recursiveChild `combine:"-"`
+	}
+}
+
+func TestProcessRecursesIntoNestedStructsAndSlices(t *testing.T) {
+	c := NewCombine()
+	c.Register("priceLookup", HandleFunc(func(values []any, ctx map[string]any) map[any]any {
+		out := make(map[any]any, len(values))
+		for idx, v := range values {
+			sku, _ := v.(string)
+			price := 1.0
+			if sku == "b" {
+				price = 2.0
+			}
+			out[idx] = price
+		}
+		return out
+	}))
+
+	p := &recursiveParent{
+		Name:     "p1",
+		Child:    recursiveChild{SKU: "a"},
+		Children: []*recursiveChild{{SKU: "a"}, {SKU: "b"}},
+	}
+	p.Deep.Skipped = recursiveChild{SKU: "a"}
+
+	if err := c.Process([]any{p}); err != nil {
+		t.Fatalf("process error: %v", err)
+	}
+	if p.Child.Price != 1.0 {
+		t.Fatalf("nested struct field not combined: %+v", p.Child)
+	}
+	if p.Children[0].Price != 1.0 || p.Children[1].Price != 2.0 {
+		t.Fatalf("nested slice elements not combined: %+v", p.Children)
+	}
+	if p.Deep.Skipped.Price != 0 {
+		t.Fatalf("combine:\"-\" field should have been skipped, got %+v", p.Deep.Skipped)
+	}
+}
+
+func TestWithMaxDepthBoundsRecursion(t *testing.T) {
+	c := NewCombine(WithMaxDepth(1))
+	c.Register("priceLookup", HandleFunc(func(values []any, ctx map[string]any) map[any]any {
+		out := make(map[any]any, len(values))
+		for idx := range values {
+			out[idx] = 9.0
+		}
+		return out
+	}))
+
+	p := &recursiveParent{Child: recursiveChild{SKU: "a"}}
+	if err := c.Process([]any{p}); err != nil {
+		t.Fatalf("process error: %v", err)
+	}
+	if p.Child.Price != 0 {
+		t.Fatalf("expected recursion to stop before nested field, got %+v", p.Child)
+	}
+}
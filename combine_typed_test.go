@@ -0,0 +1,105 @@
+package combine
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+type typedItem struct {
+	ID    int `combine:"typedLookup,Price"`
+	Price float64
+}
+
+func TestTypedRegisterAggAssertsKeysAndValues(t *testing.T) {
+	typed := NewTyped[typedItem]()
+	RegisterAgg(typed, "typedLookup", func(ctx context.Context, keys []int, cctx map[string]any) (map[int]float64, error) {
+		out := make(map[int]float64, len(keys))
+		for _, k := range keys {
+			out[k] = float64(k) * 1.5
+		}
+		return out, nil
+	})
+
+	items := []*typedItem{{ID: 2}, {ID: 4}}
+	if err := typed.Process(items); err != nil {
+		t.Fatalf("process error: %v", err)
+	}
+	if items[0].Price != 3 || items[1].Price != 6 {
+		t.Fatalf("unexpected prices: %+v %+v", items[0], items[1])
+	}
+}
+
+type typedMismatchItem struct {
+	ID  int `combine:"typedMismatch,Out"`
+	Out string
+}
+
+func TestTypedRegisterAggReportsKeyTypeMismatch(t *testing.T) {
+	typed := NewTyped[typedMismatchItem]()
+	RegisterAgg(typed, "typedMismatch", func(ctx context.Context, keys []string, cctx map[string]any) (map[string]string, error) {
+		return map[string]string{}, nil
+	})
+
+	items := []*typedMismatchItem{{ID: 1}}
+	if err := typed.Process(items); err == nil {
+		t.Fatalf("expected a type assertion error, got nil")
+	}
+}
+
+type typedStringKeyItem struct {
+	SKU   string `combine:"typedSKULookup,Price"`
+	Price float64
+}
+
+func TestTypedRegisterAggWritesBackByStringKey(t *testing.T) {
+	typed := NewTyped[typedStringKeyItem]()
+	RegisterAgg(typed, "typedSKULookup", func(ctx context.Context, keys []string, cctx map[string]any) (map[string]float64, error) {
+		out := make(map[string]float64, len(keys))
+		for _, k := range keys {
+			out[k] = float64(len(k))
+		}
+		return out, nil
+	})
+
+	items := []*typedStringKeyItem{{SKU: "a"}, {SKU: "bb"}}
+	if err := typed.Process(items); err != nil {
+		t.Fatalf("process error: %v", err)
+	}
+	if items[0].Price != 1 || items[1].Price != 2 {
+		t.Fatalf("unexpected prices: %+v %+v", items[0], items[1])
+	}
+}
+
+type plainItem struct {
+	UserID int
+	Name   string
+}
+
+func TestTypedOnResultWiresWithoutTags(t *testing.T) {
+	typed := NewTyped[plainItem]()
+	calls := 0
+	OnResult(typed, "userName",
+		func(it *plainItem) int { return it.UserID },
+		func(it *plainItem) *string { return &it.Name },
+		func(ctx context.Context, keys []int, cctx map[string]any) (map[int]string, error) {
+			calls++
+			out := make(map[int]string, len(keys))
+			for _, k := range keys {
+				out[k] = fmt.Sprintf("user-%d", k)
+			}
+			return out, nil
+		},
+	)
+
+	items := []*plainItem{{UserID: 1}, {UserID: 2}, {UserID: 1}}
+	if err := typed.Process(items); err != nil {
+		t.Fatalf("process error: %v", err)
+	}
+	if calls != 1 {
+		t.Fatalf("expected a single handler invocation across duplicate keys, got %d", calls)
+	}
+	if items[0].Name != "user-1" || items[1].Name != "user-2" || items[2].Name != "user-1" {
+		t.Fatalf("unexpected names: %+v %+v %+v", items[0], items[1], items[2])
+	}
+}
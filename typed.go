@@ -0,0 +1,150 @@
+package combine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Typed wraps a Combine so call sites work with concrete key/value types
+// instead of any and reflect type-switches. Handlers registered via
+// RegisterAgg run on the underlying Combine, so the Options passed to
+// NewTyped (concurrency, caching, timeouts) apply to them. OnResult bindings
+// do not go through that pipeline: they run one after another once it has
+// finished, and none of those Options apply to them.
+//
+// Go methods cannot introduce type parameters of their own beyond the
+// receiver's, so RegisterAgg and OnResult below are package-level functions
+// taking *Typed[T] as their first argument rather than methods on Typed[T].
+type Typed[T any] struct {
+	c *Combine
+
+	mu       sync.Mutex
+	bindings []typedRunner[T]
+}
+
+// typedRunner is a code-only (no combine tag) handler binding registered via
+// OnResult.
+type typedRunner[T any] func(ctx context.Context, items []*T, combineCtx map[string]any) error
+
+// NewTyped creates a Typed facade around a new Combine configured with opts.
+func NewTyped[T any](opts ...Option) *Typed[T] {
+	return &Typed[T]{c: New(opts...)}
+}
+
+// RegisterAgg registers a typed aggregate handler for the combine:"name,..."
+// tag found on T. fn receives the keys collected from the tagged field, each
+// asserted to K, and must return a result keyed by the actual K value (same
+// as OnResult below); Process asserts each result value into the tag's
+// output field or fn: setter, which must be of type V.
+func RegisterAgg[T any, K comparable, V any](t *Typed[T], name string, fn func(ctx context.Context, keys []K, combineCtx map[string]any) (map[K]V, error)) {
+	t.c.RegisterContext(name, HandleContextFunc(func(ctx context.Context, values []any, combineCtx map[string]any) (map[any]any, error) {
+		keys := make([]K, len(values))
+		for i, v := range values {
+			k, ok := v.(K)
+			if !ok {
+				return nil, fmt.Errorf("combine: typed handler %q: value %#v at index %d is not a %T", name, v, i, k)
+			}
+			keys[i] = k
+		}
+
+		result, err := fn(ctx, keys, combineCtx)
+		if err != nil {
+			return nil, err
+		}
+
+		out := make(map[any]any, len(keys))
+		for i, k := range keys {
+			if v, ok := result[k]; ok {
+				out[i] = v
+			}
+		}
+		return out, nil
+	}))
+}
+
+// OnResult wires a typed handler entirely in code, for callers who would
+// rather not add a combine struct tag: keySelector extracts the handler's
+// input from each item, fieldSelector returns the address Process writes
+// the matching result into. As with RegisterAgg, fn's result is keyed by the
+// actual K value; each distinct key is passed to fn once.
+func OnResult[T any, K comparable, V any](
+	t *Typed[T],
+	name string,
+	keySelector func(*T) K,
+	fieldSelector func(*T) *V,
+	fn func(ctx context.Context, keys []K, combineCtx map[string]any) (map[K]V, error),
+) {
+	t.addBinding(func(ctx context.Context, items []*T, combineCtx map[string]any) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		keys := make([]K, len(items))
+		order := make([]K, 0, len(items))
+		seen := make(map[K]bool, len(items))
+		for i, item := range items {
+			k := keySelector(item)
+			keys[i] = k
+			if !seen[k] {
+				seen[k] = true
+				order = append(order, k)
+			}
+		}
+
+		result, err := fn(ctx, order, combineCtx)
+		if err != nil {
+			return fmt.Errorf("combine: typed handler %q: %w", name, err)
+		}
+
+		for i, item := range items {
+			if v, ok := result[keys[i]]; ok {
+				*fieldSelector(item) = v
+			}
+		}
+		return nil
+	})
+}
+
+func (t *Typed[T]) addBinding(r typedRunner[T]) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.bindings = append(t.bindings, r)
+}
+
+// Process runs both the combine-tag pipeline (for handlers registered via
+// RegisterAgg) and every OnResult binding against items.
+func (t *Typed[T]) Process(items []*T) error {
+	return t.ProcessContext(context.Background(), items)
+}
+
+// ProcessContext is Process with an explicit context.Context, honored the
+// same way Combine.ProcessContext honors it: cancellation is checked before
+// each stage and propagated into handlers that accept it.
+func (t *Typed[T]) ProcessContext(ctx context.Context, items []*T) error {
+	anyItems := make([]any, len(items))
+	for i, item := range items {
+		anyItems[i] = item
+	}
+
+	var errs []error
+	if err := t.c.ProcessContext(ctx, anyItems); err != nil {
+		errs = append(errs, err)
+	}
+
+	t.c.mu.RLock()
+	combineCtx := t.c.combineCtx
+	t.c.mu.RUnlock()
+
+	t.mu.Lock()
+	bindings := append([]typedRunner[T](nil), t.bindings...)
+	t.mu.Unlock()
+
+	for _, b := range bindings {
+		if err := b(ctx, items, combineCtx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}